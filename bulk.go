@@ -0,0 +1,469 @@
+package leaderboard
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// ScoreUpdate 一次批量分数提交
+type ScoreUpdate struct {
+	PlayerID  string
+	IncrScore int64
+	Timestamp time.Time
+}
+
+// mergeScoreUpdates 把同一批次里同一玩家的多次增量合并成一次，增量累加、时间戳取最新，
+// 这样每个玩家在本批次内只需要执行一次原子更新脚本。
+func mergeScoreUpdates(updates []ScoreUpdate) ([]string, map[string]ScoreUpdate) {
+	merged := make(map[string]ScoreUpdate, len(updates))
+	order := make([]string, 0, len(updates))
+
+	for _, u := range updates {
+		if existing, ok := merged[u.PlayerID]; ok {
+			existing.IncrScore += u.IncrScore
+			if u.Timestamp.After(existing.Timestamp) {
+				existing.Timestamp = u.Timestamp
+			}
+			merged[u.PlayerID] = existing
+		} else {
+			merged[u.PlayerID] = u
+			order = append(order, u.PlayerID)
+		}
+	}
+
+	return order, merged
+}
+
+// BulkUpdateScores 把一批分数增量通过一次Pipelined请求原子地写入（标准排名）。
+// 同一玩家在批次内的多次增量会先被合并，再各自执行一次原子更新脚本。
+func (lb *RedisLeaderboard) BulkUpdateScores(ctx context.Context, updates []ScoreUpdate) ([]RankInfo, error) {
+	if len(updates) == 0 {
+		return nil, nil
+	}
+
+	order, merged := mergeScoreUpdates(updates)
+
+	if err := ensureScriptLoaded(ctx, lb.redis, &lb.mutex, &lb.scriptLoaded); err != nil {
+		return nil, fmt.Errorf("failed to load update script: %v", err)
+	}
+
+	cmds := make([]*redis.Cmd, len(order))
+	_, err := lb.redis.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, playerID := range order {
+			u := merged[playerID]
+			cmds[i] = pipe.EvalSha(ctx, updateScoreScript.Hash(), []string{lb.key, plainKey(lb.key)}, u.PlayerID, u.IncrScore, u.Timestamp.UnixNano())
+		}
+		return nil
+	})
+	if err != nil && isNoScriptErr(err) {
+		lb.mutex.Lock()
+		lb.scriptLoaded = false
+		lb.mutex.Unlock()
+		if err := ensureScriptLoaded(ctx, lb.redis, &lb.mutex, &lb.scriptLoaded); err != nil {
+			return nil, fmt.Errorf("failed to load update script: %v", err)
+		}
+		_, err = lb.redis.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			for i, playerID := range order {
+				u := merged[playerID]
+				cmds[i] = pipe.EvalSha(ctx, updateScoreScript.Hash(), []string{lb.key, plainKey(lb.key)}, u.PlayerID, u.IncrScore, u.Timestamp.UnixNano())
+			}
+			return nil
+		})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to bulk update scores: %v", err)
+	}
+
+	results := make([]RankInfo, len(order))
+	for i, playerID := range order {
+		res, err := cmds[i].Result()
+		if err != nil {
+			return nil, fmt.Errorf("failed to update score for %s: %v", playerID, err)
+		}
+
+		vals := res.([]interface{})
+		newScore := vals[0].(int64)
+		rank := int64(0)
+		if vals[1] != nil {
+			rank = vals[1].(int64) + 1
+		}
+
+		results[i] = RankInfo{
+			PlayerID:  playerID,
+			Rank:      int(rank),
+			Score:     newScore,
+			Timestamp: merged[playerID].Timestamp,
+		}
+	}
+
+	return results, nil
+}
+
+// GetPlayerRanks 批量查询玩家当前排名（标准排名），通过一次Pipelined请求拉取所有
+// ZSCORE+ZRANK组合，而不是对每个玩家单独往返一次。
+func (lb *RedisLeaderboard) GetPlayerRanks(ctx context.Context, playerIDs []string) []RankInfo {
+	if len(playerIDs) == 0 {
+		return nil
+	}
+
+	scoreCmds := make([]*redis.FloatCmd, len(playerIDs))
+	rankCmds := make([]*redis.IntCmd, len(playerIDs))
+	_, err := lb.redis.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, playerID := range playerIDs {
+			scoreCmds[i] = pipe.ZScore(ctx, lb.key, playerID)
+			rankCmds[i] = pipe.ZRank(ctx, lb.key, playerID)
+		}
+		return nil
+	})
+	if err != nil && err != redis.Nil {
+		return nil
+	}
+
+	result := make([]RankInfo, 0, len(playerIDs))
+	for i, playerID := range playerIDs {
+		score, err := scoreCmds[i].Result()
+		if err != nil {
+			continue
+		}
+
+		rank := int64(0)
+		if r, err := rankCmds[i].Result(); err == nil {
+			rank = r + 1
+		}
+
+		result = append(result, RankInfo{
+			PlayerID:  playerID,
+			Rank:      int(rank),
+			Score:     int64(-score / 1e9),
+			Timestamp: time.Now(),
+		})
+	}
+
+	return result
+}
+
+// BulkUpdateScores 把一批分数增量通过一次Pipelined请求原子地写入（密集排名）。
+func (lb *DenseRedisLeaderboard) BulkUpdateScores(ctx context.Context, updates []ScoreUpdate) ([]RankInfo, error) {
+	if len(updates) == 0 {
+		return nil, nil
+	}
+
+	order, merged := mergeScoreUpdates(updates)
+
+	if err := ensureScriptLoaded(ctx, lb.redis, &lb.mutex, &lb.scriptLoaded); err != nil {
+		return nil, fmt.Errorf("failed to load update script: %v", err)
+	}
+
+	cmds := make([]*redis.Cmd, len(order))
+	_, err := lb.redis.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, playerID := range order {
+			u := merged[playerID]
+			cmds[i] = pipe.EvalSha(ctx, updateScoreScript.Hash(), []string{lb.key, plainKey(lb.key)}, u.PlayerID, u.IncrScore, u.Timestamp.UnixNano())
+		}
+		return nil
+	})
+	if err != nil && isNoScriptErr(err) {
+		lb.mutex.Lock()
+		lb.scriptLoaded = false
+		lb.mutex.Unlock()
+		if err := ensureScriptLoaded(ctx, lb.redis, &lb.mutex, &lb.scriptLoaded); err != nil {
+			return nil, fmt.Errorf("failed to load update script: %v", err)
+		}
+		_, err = lb.redis.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			for i, playerID := range order {
+				u := merged[playerID]
+				cmds[i] = pipe.EvalSha(ctx, updateScoreScript.Hash(), []string{lb.key, plainKey(lb.key)}, u.PlayerID, u.IncrScore, u.Timestamp.UnixNano())
+			}
+			return nil
+		})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to bulk update scores: %v", err)
+	}
+
+	results := make([]RankInfo, len(order))
+	for i, playerID := range order {
+		res, err := cmds[i].Result()
+		if err != nil {
+			return nil, fmt.Errorf("failed to update score for %s: %v", playerID, err)
+		}
+
+		vals := res.([]interface{})
+		results[i] = RankInfo{
+			PlayerID:  playerID,
+			Score:     vals[0].(int64),
+			Timestamp: merged[playerID].Timestamp,
+		}
+	}
+
+	// 密集排名需要在写入后按打包分数重新统计名次，这里用两次Pipelined批量完成（先取打包分数，再ZCOUNT）
+	scoreCmds := make([]*redis.FloatCmd, len(order))
+	_, err = lb.redis.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, playerID := range order {
+			scoreCmds[i] = pipe.ZScore(ctx, lb.key, playerID)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch packed scores: %v", err)
+	}
+
+	countCmds := make([]*redis.IntCmd, len(order))
+	_, err = lb.redis.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i := range order {
+			packedScore, err := scoreCmds[i].Result()
+			if err != nil {
+				continue
+			}
+			countCmds[i] = pipe.ZCount(ctx, lb.key, "-inf", fmt.Sprintf("%.f", packedScore))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute dense ranks: %v", err)
+	}
+
+	for i := range order {
+		if countCmds[i] == nil {
+			continue
+		}
+		if count, err := countCmds[i].Result(); err == nil {
+			results[i].Rank = int(count)
+		}
+	}
+
+	return results, nil
+}
+
+// GetPlayerRanks 批量查询玩家当前排名（密集排名）
+func (lb *DenseRedisLeaderboard) GetPlayerRanks(ctx context.Context, playerIDs []string) []RankInfo {
+	if len(playerIDs) == 0 {
+		return nil
+	}
+
+	scoreCmds := make([]*redis.FloatCmd, len(playerIDs))
+	_, err := lb.redis.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, playerID := range playerIDs {
+			scoreCmds[i] = pipe.ZScore(ctx, lb.key, playerID)
+		}
+		return nil
+	})
+	if err != nil && err != redis.Nil {
+		return nil
+	}
+
+	countCmds := make([]*redis.IntCmd, len(playerIDs))
+	_, err = lb.redis.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i := range playerIDs {
+			score, serr := scoreCmds[i].Result()
+			if serr != nil {
+				continue
+			}
+			countCmds[i] = pipe.ZCount(ctx, lb.key, "-inf", fmt.Sprintf("%.f", score))
+		}
+		return nil
+	})
+	if err != nil && err != redis.Nil {
+		return nil
+	}
+
+	result := make([]RankInfo, 0, len(playerIDs))
+	for i, playerID := range playerIDs {
+		score, err := scoreCmds[i].Result()
+		if err != nil {
+			continue
+		}
+
+		rank := 0
+		if countCmds[i] != nil {
+			if count, err := countCmds[i].Result(); err == nil {
+				rank = int(count)
+			}
+		}
+
+		result = append(result, RankInfo{
+			PlayerID:  playerID,
+			Rank:      rank,
+			Score:     int64(-score / 1e9),
+			Timestamp: time.Now(),
+		})
+	}
+
+	return result
+}
+
+// scoreSubmitter 是 BulkUpdater 刷新批次时需要的最小能力
+type scoreSubmitter interface {
+	BulkUpdateScores(ctx context.Context, updates []ScoreUpdate) ([]RankInfo, error)
+}
+
+// BulkUpdaterConfig BulkUpdater的缓冲/刷新参数
+type BulkUpdaterConfig struct {
+	MaxBatchSize    int           // 单次flush最多携带多少条更新，默认500；达到该值立即flush，不等ticker
+	FlushInterval   time.Duration // 定时flush的周期，默认100ms
+	BufferSize      int           // Submit缓冲channel的容量，默认MaxBatchSize*4；channel满时Submit阻塞，形成背压
+	MaxRetries      int           // 一个批次flush失败后的重试次数，默认2；重试仍失败则投递到错误channel
+	RetryBackoff    time.Duration // 重试前的等待时间，默认50ms
+	ErrorBufferSize int           // Errors()错误channel的容量，默认16；写满后最老的批次会被丢弃并打印警告
+}
+
+// BulkFlushError 记录一次重试耗尽后仍然失败的批次，供调用方通过 Errors() 获取后自行
+// 决定重新入队、落盘或告警，而不是像早期实现那样直接丢弃。
+type BulkFlushError struct {
+	Updates []ScoreUpdate
+	Err     error
+}
+
+// BulkUpdater 把高频的逐条UpdateScore调用聚合成批量Pipelined请求，
+// 用于服务器tick场景下每秒提交上千次分数增量，比同步路径有数量级的吞吐提升。
+type BulkUpdater struct {
+	submitter    scoreSubmitter
+	updates      chan ScoreUpdate
+	maxBatch     int
+	interval     time.Duration
+	maxRetries   int
+	retryBackoff time.Duration
+
+	errCh chan BulkFlushError
+
+	stopOnce sync.Once
+	stopCh   chan struct{}
+	doneCh   chan struct{}
+}
+
+// NewBulkUpdater 创建一个后台flusher并立即启动
+func NewBulkUpdater(lb scoreSubmitter, config BulkUpdaterConfig) *BulkUpdater {
+	maxBatch := config.MaxBatchSize
+	if maxBatch <= 0 {
+		maxBatch = 500
+	}
+	interval := config.FlushInterval
+	if interval <= 0 {
+		interval = 100 * time.Millisecond
+	}
+	bufferSize := config.BufferSize
+	if bufferSize <= 0 {
+		bufferSize = maxBatch * 4
+	}
+	maxRetries := config.MaxRetries
+	if maxRetries <= 0 {
+		maxRetries = 2
+	}
+	retryBackoff := config.RetryBackoff
+	if retryBackoff <= 0 {
+		retryBackoff = 50 * time.Millisecond
+	}
+	errorBufferSize := config.ErrorBufferSize
+	if errorBufferSize <= 0 {
+		errorBufferSize = 16
+	}
+
+	u := &BulkUpdater{
+		submitter:    lb,
+		updates:      make(chan ScoreUpdate, bufferSize),
+		maxBatch:     maxBatch,
+		interval:     interval,
+		maxRetries:   maxRetries,
+		retryBackoff: retryBackoff,
+		errCh:        make(chan BulkFlushError, errorBufferSize),
+		stopCh:       make(chan struct{}),
+		doneCh:       make(chan struct{}),
+	}
+
+	go u.run()
+
+	return u
+}
+
+// Submit 把一次分数增量投递进缓冲channel；channel满时阻塞调用方，天然形成背压
+func (u *BulkUpdater) Submit(update ScoreUpdate) {
+	u.updates <- update
+}
+
+// Errors 返回重试耗尽后仍然失败的批次；调用方应持续消费该channel，否则写满后
+// 最老的批次会被丢弃（此时仅打印一行警告，作为最后的兜底）。
+func (u *BulkUpdater) Errors() <-chan BulkFlushError {
+	return u.errCh
+}
+
+func (u *BulkUpdater) run() {
+	defer close(u.doneCh)
+
+	ticker := time.NewTicker(u.interval)
+	defer ticker.Stop()
+
+	batch := make([]ScoreUpdate, 0, u.maxBatch)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+
+		var err error
+		for attempt := 0; attempt <= u.maxRetries; attempt++ {
+			if attempt > 0 {
+				time.Sleep(u.retryBackoff)
+			}
+			if _, err = u.submitter.BulkUpdateScores(context.Background(), batch); err == nil {
+				break
+			}
+		}
+		if err != nil {
+			failed := make([]ScoreUpdate, len(batch))
+			copy(failed, batch)
+			flushErr := BulkFlushError{Updates: failed, Err: err}
+			select {
+			case u.errCh <- flushErr:
+			default:
+				// 错误channel已满：丢弃最老的批次，为本批次腾出位置
+				select {
+				case dropped := <-u.errCh:
+					fmt.Printf("Warning: bulk flush error channel is full, dropping oldest batch: %v\n", dropped.Err)
+				default:
+				}
+				select {
+				case u.errCh <- flushErr:
+				default:
+				}
+			}
+		}
+		batch = batch[:0]
+	}
+
+	for {
+		select {
+		case <-u.stopCh:
+			// 退出前排空缓冲channel中剩余的更新
+			for {
+				select {
+				case update := <-u.updates:
+					batch = append(batch, update)
+					if len(batch) >= u.maxBatch {
+						flush()
+					}
+				default:
+					flush()
+					return
+				}
+			}
+		case update := <-u.updates:
+			batch = append(batch, update)
+			if len(batch) >= u.maxBatch {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+// Stop 停止后台flusher，并阻塞到缓冲区中剩余的更新都被刷入为止
+func (u *BulkUpdater) Stop() {
+	u.stopOnce.Do(func() {
+		close(u.stopCh)
+	})
+	<-u.doneCh
+}
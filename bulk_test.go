@@ -0,0 +1,50 @@
+package leaderboard
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// TestMergeScoreUpdates 验证同一玩家在批次内的多次增量会被合并成一次：增量累加、
+// 时间戳取最新，且保留玩家首次出现的顺序。纯逻辑，不依赖Redis。
+func TestMergeScoreUpdates(t *testing.T) {
+	t0 := time.Now()
+	t1 := t0.Add(time.Second)
+	t2 := t0.Add(2 * time.Second)
+
+	updates := []ScoreUpdate{
+		{PlayerID: "p1", IncrScore: 10, Timestamp: t0},
+		{PlayerID: "p2", IncrScore: 5, Timestamp: t1},
+		{PlayerID: "p1", IncrScore: 3, Timestamp: t2},
+		{PlayerID: "p1", IncrScore: 2, Timestamp: t0},
+	}
+
+	order, merged := mergeScoreUpdates(updates)
+
+	wantOrder := []string{"p1", "p2"}
+	if !reflect.DeepEqual(order, wantOrder) {
+		t.Fatalf("expected order %v, got %v", wantOrder, order)
+	}
+
+	p1 := merged["p1"]
+	if p1.IncrScore != 15 {
+		t.Fatalf("expected p1 merged IncrScore 15, got %d", p1.IncrScore)
+	}
+	if !p1.Timestamp.Equal(t2) {
+		t.Fatalf("expected p1 merged Timestamp %v, got %v", t2, p1.Timestamp)
+	}
+
+	p2 := merged["p2"]
+	if p2.IncrScore != 5 {
+		t.Fatalf("expected p2 merged IncrScore 5, got %d", p2.IncrScore)
+	}
+}
+
+// TestMergeScoreUpdatesEmpty 空输入应返回空的顺序和映射，而不是nil解引用之类的问题
+func TestMergeScoreUpdatesEmpty(t *testing.T) {
+	order, merged := mergeScoreUpdates(nil)
+	if len(order) != 0 || len(merged) != 0 {
+		t.Fatalf("expected empty order/merged for empty input, got order=%v merged=%v", order, merged)
+	}
+}
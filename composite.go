@@ -0,0 +1,130 @@
+package leaderboard
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// compositeMode 组合方式
+type compositeMode int
+
+const (
+	compositeUnion compositeMode = iota
+	compositeIntersection
+)
+
+// CompositeLeaderboard 组合排行榜：通过 ZUNIONSTORE/ZINTERSTORE 把多个来源排行榜
+// （如日榜+周榜+月榜，或多个玩法榜）聚合成一个派生排名。
+//
+// 由于各来源榜把分数和时间戳打包进了同一个浮点分值，聚合不能直接对打包分值做
+// 加权求和，否则时间戳会污染结果。Refresh 先对每个来源的"原始分数"并行ZSET
+// （见 plainKey）做 ZUNIONSTORE/ZINTERSTORE，再用当前时间重新打包写入目标key，
+// 之后即可像普通排行榜一样用 GetTopN/GetPlayerRank/GetPlayerRange 查询。
+type CompositeLeaderboard struct {
+	*RedisLeaderboard
+	sourceKeys []string
+	weights    []float64
+	aggregate  string
+	mode       compositeMode
+}
+
+// NewWeightedUnion 创建一个加权并集组合排行榜，例如 "赛季分 = 3×日榜 + 1×周榜"。
+// aggregate 为 Redis 的聚合方式："SUM"、"MIN" 或 "MAX"，留空默认为 "SUM"。
+// 集群模式下，keys 和 destKey 必须共用同一个哈希标签，否则 ZUNIONSTORE/ZINTERSTORE 会报 CROSSSLOT。
+func NewWeightedUnion(client redis.UniversalClient, keys []string, weights []float64, aggregate string, destKey string) *CompositeLeaderboard {
+	if aggregate == "" {
+		aggregate = "SUM"
+	}
+
+	return &CompositeLeaderboard{
+		RedisLeaderboard: &RedisLeaderboard{redis: client, key: destKey},
+		sourceKeys:       keys,
+		weights:          weights,
+		aggregate:        aggregate,
+		mode:             compositeUnion,
+	}
+}
+
+// NewIntersection 创建一个交集组合排行榜，仅保留在所有来源榜中都出现过的玩家。
+func NewIntersection(client redis.UniversalClient, keys []string, weights []float64, aggregate string, destKey string) *CompositeLeaderboard {
+	if aggregate == "" {
+		aggregate = "SUM"
+	}
+
+	return &CompositeLeaderboard{
+		RedisLeaderboard: &RedisLeaderboard{redis: client, key: destKey},
+		sourceKeys:       keys,
+		weights:          weights,
+		aggregate:        aggregate,
+		mode:             compositeIntersection,
+	}
+}
+
+// Refresh 重新计算组合排行榜：聚合各来源的原始分数，再用新时间戳重新打包写回目标key。
+func (c *CompositeLeaderboard) Refresh(ctx context.Context) error {
+	plainSources := make([]string, len(c.sourceKeys))
+	for i, key := range c.sourceKeys {
+		plainSources[i] = plainKey(key)
+	}
+	plainDest := plainKey(c.key)
+
+	store := &redis.ZStore{
+		Keys:      plainSources,
+		Weights:   c.weights,
+		Aggregate: c.aggregate,
+	}
+
+	var err error
+	switch c.mode {
+	case compositeUnion:
+		err = c.redis.ZUnionStore(ctx, plainDest, store).Err()
+	case compositeIntersection:
+		err = c.redis.ZInterStore(ctx, plainDest, store).Err()
+	}
+	if err != nil {
+		return fmt.Errorf("failed to aggregate composite leaderboard %s: %v", c.key, err)
+	}
+
+	members, err := c.redis.ZRangeWithScores(ctx, plainDest, 0, -1).Result()
+	if err != nil {
+		return fmt.Errorf("failed to read aggregated scores for %s: %v", c.key, err)
+	}
+
+	if len(members) == 0 {
+		if err := c.redis.Del(ctx, c.key).Err(); err != nil {
+			return fmt.Errorf("failed to clear composite leaderboard %s: %v", c.key, err)
+		}
+		return nil
+	}
+
+	// 先把重新打包的结果写入一个临时key，再用RENAME原子地替换目标key，避免
+	// Del+多次ZAdd之间出现并发读者读到空榜或半量榜的窗口。临时key以目标key为
+	// 前缀，保留其花括号哈希标签，确保和目标key落在同一个槽位，RENAME可用。
+	now := time.Now()
+	tempKey := c.key + ":refreshing"
+
+	pipe := c.redis.Pipeline()
+	pipe.Del(ctx, tempKey)
+	for _, member := range members {
+		packed := -(member.Score*1e9 + float64(now.UnixNano())/1e9)
+		pipe.ZAdd(ctx, tempKey, &redis.Z{Score: packed, Member: member.Member})
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to stage composite leaderboard %s: %v", c.key, err)
+	}
+
+	if err := c.redis.Rename(ctx, tempKey, c.key).Err(); err != nil {
+		return fmt.Errorf("failed to swap in composite leaderboard %s: %v", c.key, err)
+	}
+
+	return nil
+}
+
+// Close 组合排行榜的底层client是调用方传入并管理的共享连接（可能还有其他排行榜
+// 句柄在用），这里特意不关闭它，覆盖掉内嵌RedisLeaderboard.Close()会关闭连接的行为。
+func (c *CompositeLeaderboard) Close() error {
+	return nil
+}
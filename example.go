@@ -1,6 +1,7 @@
 package leaderboard
 
 import (
+	"context"
 	"fmt"
 	"time"
 )
@@ -8,6 +9,8 @@ import (
 func TestLeaderboard() {
 	fmt.Println("=== 基于Redis的分布式排行榜系统 ===\n")
 
+	ctx := context.Background()
+
 	// 创建Redis配置
 	config := Config{
 		RedisAddr:      "localhost:6379",
@@ -29,26 +32,37 @@ func TestLeaderboard() {
 
 	// 测试数据
 	now := time.Now()
-	standardLB.UpdateScore("玩家A", 100, now)
-	standardLB.UpdateScore("玩家B", 200, now.Add(time.Second))
-	standardLB.UpdateScore("玩家C", 150, now.Add(2*time.Second))
-	standardLB.UpdateScore("玩家D", 200, now.Add(3*time.Second)) // 相同分数，时间更晚
+	updates := []struct {
+		playerID string
+		score    int64
+		ts       time.Time
+	}{
+		{"玩家A", 100, now},
+		{"玩家B", 200, now.Add(time.Second)},
+		{"玩家C", 150, now.Add(2 * time.Second)},
+		{"玩家D", 200, now.Add(3 * time.Second)}, // 相同分数，时间更晚
+	}
+	for _, u := range updates {
+		if _, err := standardLB.UpdateScore(ctx, u.playerID, u.score, u.ts); err != nil {
+			fmt.Printf("Warning: %v\n", err)
+		}
+	}
 
 	time.Sleep(100 * time.Millisecond) // 等待Redis写入
 
 	fmt.Println("前3名玩家（标准排名）：")
-	top3 := standardLB.GetTopN(3)
+	top3 := standardLB.GetTopN(ctx, 3)
 	for _, player := range top3 {
 		fmt.Printf("  第%d名: %s (分数: %d)\n", player.Rank, player.PlayerID, player.Score)
 	}
 
-	rankInfo := standardLB.GetPlayerRank("玩家B")
+	rankInfo := standardLB.GetPlayerRank(ctx, "玩家B")
 	if rankInfo != nil {
 		fmt.Printf("\n玩家B的排名: 第%d名 (分数: %d)\n", rankInfo.Rank, rankInfo.Score)
 	}
 
 	fmt.Println("\n玩家B前后1名：")
-	rangeInfo := standardLB.GetPlayerRange("玩家B", 1)
+	rangeInfo := standardLB.GetPlayerRange(ctx, "玩家B", 1)
 	for _, player := range rangeInfo {
 		fmt.Printf("  第%d名: %s (分数: %d)\n", player.Rank, player.PlayerID, player.Score)
 	}
@@ -64,17 +78,28 @@ func TestLeaderboard() {
 	defer denseLB.Close()
 
 	// 添加测试数据（题目示例）
-	denseLB.UpdateScore("玩家A", 100, now)
-	denseLB.UpdateScore("玩家B", 100, now.Add(time.Second))
-	denseLB.UpdateScore("玩家C", 95, now.Add(2*time.Second))
-	denseLB.UpdateScore("玩家D", 95, now.Add(3*time.Second))
-	denseLB.UpdateScore("玩家E", 90, now.Add(4*time.Second))
-	denseLB.UpdateScore("玩家F", 89, now.Add(5*time.Second))
+	denseUpdates := []struct {
+		playerID string
+		score    int64
+		ts       time.Time
+	}{
+		{"玩家A", 100, now},
+		{"玩家B", 100, now.Add(time.Second)},
+		{"玩家C", 95, now.Add(2 * time.Second)},
+		{"玩家D", 95, now.Add(3 * time.Second)},
+		{"玩家E", 90, now.Add(4 * time.Second)},
+		{"玩家F", 89, now.Add(5 * time.Second)},
+	}
+	for _, u := range denseUpdates {
+		if _, err := denseLB.UpdateScore(ctx, u.playerID, u.score, u.ts); err != nil {
+			fmt.Printf("Warning: %v\n", err)
+		}
+	}
 
 	time.Sleep(100 * time.Millisecond)
 
 	fmt.Println("密集排名结果：")
-	allPlayers := denseLB.GetTopN(6)
+	allPlayers := denseLB.GetTopN(ctx, 6)
 	for _, player := range allPlayers {
 		fmt.Printf("  第%d名: %s (分数: %d)\n", player.Rank, player.PlayerID, player.Score)
 	}
@@ -87,7 +112,7 @@ func TestLeaderboard() {
 	fmt.Println("- ✅ 数据持久化")
 	fmt.Println("- ✅ 支持集群部署")
 
-	stats := standardLB.GetStatistics()
+	stats := standardLB.GetStatistics(ctx)
 	fmt.Printf("\n统计信息: %+v\n", stats)
 
 	fmt.Println("\n=== 使用说明 ===")
@@ -18,50 +18,179 @@ type Player struct {
 
 // RankInfo 排名信息
 type RankInfo struct {
-	PlayerID  string    `json:"player_id"`
-	Rank      int       `json:"rank"`
-	Score     int64     `json:"score"`
-	Timestamp time.Time `json:"timestamp"`
+	PlayerID  string            `json:"player_id"`
+	Rank      int               `json:"rank"`
+	Score     int64             `json:"score"`
+	Timestamp time.Time         `json:"timestamp"`
+	Profile   map[string]string `json:"profile,omitempty"` // 玩家资料（昵称、头像等），仅在查询到时填充
 }
 
 // LeaderboardService 排行榜服务接口
 type LeaderboardService interface {
-	// UpdateScore 更新玩家分数
-	UpdateScore(playerID string, incrScore int64, timestamp time.Time)
+	// UpdateScore 原子地更新玩家分数，返回更新后的排名信息
+	UpdateScore(ctx context.Context, playerID string, incrScore int64, timestamp time.Time) (*RankInfo, error)
 
 	// GetPlayerRank 获取玩家当前排名
-	GetPlayerRank(playerID string) *RankInfo
+	GetPlayerRank(ctx context.Context, playerID string) *RankInfo
 
 	// GetTopN 获取排行榜前N名
-	GetTopN(n int) []RankInfo
+	GetTopN(ctx context.Context, n int) []RankInfo
 
 	// GetPlayerRange 查询自己名次前后共N名玩家的分数和名次
-	GetPlayerRange(playerID string, n int) []RankInfo
+	GetPlayerRange(ctx context.Context, playerID string, n int) []RankInfo
+}
+
+// updateScoreScript 原子地读取旧分数、累加增量并重新打包写回，
+// 避免 ZSCORE 和 ZADD 分两步执行时并发更新同一玩家导致的丢失更新。
+// 同时维护一个未打包的"原始分数"并行ZSET（KEYS[2]），供组合排行榜做
+// ZUNIONSTORE/ZINTERSTORE 聚合时直接使用，无需再解包。
+// KEYS[1] = 排行榜key, KEYS[2] = 原始分数并行key, ARGV[1] = playerID,
+// ARGV[2] = incrScore, ARGV[3] = 时间戳(纳秒)
+// 返回 {newScore, rank}（rank 为 ZRANK 的 0 基结果）
+//
+// 集群模式下 KEYS[1]/KEYS[2] 必须落在同一个哈希槽，见 Config.LeaderboardKey 的说明。
+var updateScoreScript = redis.NewScript(`
+local current = redis.call('ZSCORE', KEYS[1], ARGV[1])
+local currentScore = 0
+if current then
+	currentScore = math.floor(-tonumber(current) / 1e9)
+end
+local newScore = currentScore + tonumber(ARGV[2])
+local packed = -(newScore * 1e9 + tonumber(ARGV[3]) / 1e9)
+redis.call('ZADD', KEYS[1], packed, ARGV[1])
+redis.call('ZADD', KEYS[2], newScore, ARGV[1])
+local rank = redis.call('ZRANK', KEYS[1], ARGV[1])
+return {newScore, rank}
+`)
+
+// plainKey 返回某个排行榜key对应的"原始分数"并行key，用于跨榜聚合。
+// 和排行榜key本身一样，必须和它落在同一个哈希槽（共用花括号标签）。
+func plainKey(key string) string {
+	return key + ":plain"
+}
+
+// ensureScriptLoaded 确保更新脚本已经 SCRIPT LOAD 过，成功后只加载一次，
+// 避免BulkUpdateScores等高频路径每次调用都往返一次 SCRIPT LOAD。
+// mutex/loaded 由调用方传入各自排行榜实例上的字段，与manager.go的同名逻辑共用同一模式。
+func ensureScriptLoaded(ctx context.Context, client redis.UniversalClient, mutex *sync.RWMutex, loaded *bool) error {
+	mutex.RLock()
+	ok := *loaded
+	mutex.RUnlock()
+	if ok {
+		return nil
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	if *loaded {
+		return nil
+	}
+	if err := updateScoreScript.Load(ctx, client).Err(); err != nil {
+		return err
+	}
+	*loaded = true
+	return nil
 }
 
+// Mode 表示连接Redis的部署形态
+type Mode int
+
+const (
+	// ModeStandalone 单机/主从（默认）
+	ModeStandalone Mode = iota
+	// ModeCluster Redis Cluster，使用 Addrs 中的节点做拓扑发现
+	ModeCluster
+	// ModeSentinel Redis Sentinel高可用，通过 MasterName + Addrs（哨兵地址）定位主节点
+	ModeSentinel
+	// ModeManualClusterSlots 手工指定集群槽位路由，不依赖Redis自身的拓扑发现
+	ModeManualClusterSlots
+)
+
 // RedisLeaderboard Redis分布式排行榜实现
 type RedisLeaderboard struct {
-	redis *redis.Client
-	mutex sync.RWMutex
-	key   string
+	redis         redis.UniversalClient
+	mutex         sync.RWMutex
+	key           string
+	addr          string   // 展示用：连接地址（集群/哨兵模式下取Addrs[0]）
+	profileFields []string // 限制GetTopN/GetPlayerRange拉取的玩家资料字段，留空则拉取全部
+	scriptLoaded  bool     // BulkUpdateScores是否已经SCRIPT LOAD过更新脚本，见ensureScriptLoaded
 }
 
 // Config Redis排行榜配置
+//
+// LeaderboardKey 对应的ZSET中的所有成员必须落在同一个哈希槽内，因为排名查询
+// （ZRANK/ZRANGE）要求整个key在单个节点上完成；在Cluster模式下请用花括号
+// 标签固定槽位，例如 "{season1}:leaderboard"。跨分片聚合（见CompositeLeaderboard）
+// 同理要求所有来源key共用同一个哈希标签，聚合改用 ZUNIONSTORE 完成。
 type Config struct {
 	RedisAddr      string
 	RedisPassword  string
 	RedisDB        int
 	LeaderboardKey string
 	IsDenseRanking bool // 是否为密集排名
+
+	Mode         Mode                                                   // 部署模式，默认ModeStandalone
+	Addrs        []string                                               // Cluster节点地址或Sentinel哨兵地址
+	MasterName   string                                                 // Sentinel模式下的主节点名
+	ClusterSlots func(ctx context.Context) ([]redis.ClusterSlot, error) // ModeManualClusterSlots下的手工槽位路由
+	PoolSize     int                                                    // 每个节点的连接池大小，0表示使用go-redis默认值
+
+	// ProfileFields 限制 GetTopN/GetPlayerRange 拉取的玩家资料字段（走HMGET）。
+	// 留空则拉取资料HASH的全部字段（走HGETALL）。
+	ProfileFields []string
+}
+
+// newUniversalClient 根据Mode构造对应的redis.UniversalClient实现
+// （单机redis.Client、集群redis.ClusterClient或哨兵redis.FailoverClient）
+func newUniversalClient(config Config) (redis.UniversalClient, error) {
+	switch config.Mode {
+	case ModeCluster:
+		return redis.NewClusterClient(&redis.ClusterOptions{
+			Addrs:    config.Addrs,
+			Password: config.RedisPassword,
+			PoolSize: config.PoolSize,
+		}), nil
+	case ModeManualClusterSlots:
+		if config.ClusterSlots == nil {
+			return nil, fmt.Errorf("ClusterSlots is required for ModeManualClusterSlots")
+		}
+		return redis.NewClusterClient(&redis.ClusterOptions{
+			Password:     config.RedisPassword,
+			PoolSize:     config.PoolSize,
+			ClusterSlots: config.ClusterSlots,
+		}), nil
+	case ModeSentinel:
+		return redis.NewFailoverClient(&redis.FailoverOptions{
+			MasterName:    config.MasterName,
+			SentinelAddrs: config.Addrs,
+			Password:      config.RedisPassword,
+			DB:            config.RedisDB,
+			PoolSize:      config.PoolSize,
+		}), nil
+	default:
+		return redis.NewClient(&redis.Options{
+			Addr:     config.RedisAddr,
+			Password: config.RedisPassword,
+			DB:       config.RedisDB,
+			PoolSize: config.PoolSize,
+		}), nil
+	}
+}
+
+// displayAddr 返回用于统计展示的连接地址，不影响实际路由
+func displayAddr(config Config) string {
+	if len(config.Addrs) > 0 {
+		return config.Addrs[0]
+	}
+	return config.RedisAddr
 }
 
 // NewLeaderboard 创建Redis分布式排行榜
 func NewLeaderboard(config Config) (*RedisLeaderboard, error) {
-	client := redis.NewClient(&redis.Options{
-		Addr:     config.RedisAddr,
-		Password: config.RedisPassword,
-		DB:       config.RedisDB,
-	})
+	client, err := newUniversalClient(config)
+	if err != nil {
+		return nil, err
+	}
 
 	// 测试连接
 	ctx := context.Background()
@@ -75,41 +204,37 @@ func NewLeaderboard(config Config) (*RedisLeaderboard, error) {
 	}
 
 	return &RedisLeaderboard{
-		redis: client,
-		key:   key,
+		redis:         client,
+		key:           key,
+		addr:          displayAddr(config),
+		profileFields: config.ProfileFields,
 	}, nil
 }
 
-// UpdateScore 更新玩家分数
-func (lb *RedisLeaderboard) UpdateScore(playerID string, incrScore int64, timestamp time.Time) {
-	ctx := context.Background()
-
-	// 获取当前分数
-	currentScore, err := lb.redis.ZScore(ctx, lb.key, playerID).Result()
-	if err != nil && err != redis.Nil {
-		fmt.Printf("Warning: failed to get current score for %s: %v\n", playerID, err)
-		currentScore = 0
+// UpdateScore 原子地更新玩家分数（通过 Lua 脚本消除 ZSCORE+ZADD 的读-改-写竞态）
+func (lb *RedisLeaderboard) UpdateScore(ctx context.Context, playerID string, incrScore int64, timestamp time.Time) (*RankInfo, error) {
+	res, err := updateScoreScript.Run(ctx, lb.redis, []string{lb.key, plainKey(lb.key)}, playerID, incrScore, timestamp.UnixNano()).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to update score for %s: %v", playerID, err)
 	}
 
-	// 计算新分数
-	newScore := currentScore + float64(incrScore)
-
-	// 使用负数保证高分数在高位，加上时间戳保证同分时的时间排序
-	// 分数相同时，先得到该分数的玩家排在前面
-	scoreWithTimestamp := -(newScore*1e9 + float64(timestamp.UnixNano())/1e9)
-
-	if err := lb.redis.ZAdd(ctx, lb.key, &redis.Z{
-		Score:  scoreWithTimestamp,
-		Member: playerID,
-	}).Err(); err != nil {
-		fmt.Printf("Warning: failed to update score for %s: %v\n", playerID, err)
+	vals := res.([]interface{})
+	newScore := vals[0].(int64)
+	rank := int64(0)
+	if vals[1] != nil {
+		rank = vals[1].(int64) + 1
 	}
+
+	return &RankInfo{
+		PlayerID:  playerID,
+		Rank:      int(rank),
+		Score:     newScore,
+		Timestamp: timestamp,
+	}, nil
 }
 
 // GetPlayerRank 获取玩家排名（标准排名）
-func (lb *RedisLeaderboard) GetPlayerRank(playerID string) *RankInfo {
-	ctx := context.Background()
-
+func (lb *RedisLeaderboard) GetPlayerRank(ctx context.Context, playerID string) *RankInfo {
 	// 获取分数
 	score, err := lb.redis.ZScore(ctx, lb.key, playerID).Result()
 	if err != nil {
@@ -134,9 +259,7 @@ func (lb *RedisLeaderboard) GetPlayerRank(playerID string) *RankInfo {
 }
 
 // GetTopN 获取前N名（标准排名）
-func (lb *RedisLeaderboard) GetTopN(n int) []RankInfo {
-	ctx := context.Background()
-
+func (lb *RedisLeaderboard) GetTopN(ctx context.Context, n int) []RankInfo {
 	// 从Redis获取前N名
 	members, err := lb.redis.ZRangeWithScores(ctx, lb.key, 0, int64(n-1)).Result()
 	if err != nil {
@@ -156,13 +279,11 @@ func (lb *RedisLeaderboard) GetTopN(n int) []RankInfo {
 		})
 	}
 
-	return result
+	return attachProfiles(ctx, lb.redis, lb.key, lb.profileFields, result)
 }
 
 // GetPlayerRange 查询玩家前后N名（标准排名）
-func (lb *RedisLeaderboard) GetPlayerRange(playerID string, n int) []RankInfo {
-	ctx := context.Background()
-
+func (lb *RedisLeaderboard) GetPlayerRange(ctx context.Context, playerID string, n int) []RankInfo {
 	// 获取玩家排名
 	playerRank, err := lb.redis.ZRank(ctx, lb.key, playerID).Result()
 	if err != nil {
@@ -195,18 +316,17 @@ func (lb *RedisLeaderboard) GetPlayerRange(playerID string, n int) []RankInfo {
 		})
 	}
 
-	return result
+	return attachProfiles(ctx, lb.redis, lb.key, lb.profileFields, result)
 }
 
 // GetStatistics 获取排行榜统计
-func (lb *RedisLeaderboard) GetStatistics() map[string]interface{} {
-	ctx := context.Background()
+func (lb *RedisLeaderboard) GetStatistics(ctx context.Context) map[string]interface{} {
 	playerCount := lb.redis.ZCard(ctx, lb.key).Val()
 
 	return map[string]interface{}{
 		"total_players":   playerCount,
 		"leaderboard_key": lb.key,
-		"redis_addr":      lb.redis.Options().Addr,
+		"redis_addr":      lb.addr,
 	}
 }
 
@@ -217,18 +337,20 @@ func (lb *RedisLeaderboard) Close() error {
 
 // DenseRedisLeaderboard Redis密集排名排行榜实现
 type DenseRedisLeaderboard struct {
-	redis *redis.Client
-	mutex sync.RWMutex
-	key   string
+	redis         redis.UniversalClient
+	mutex         sync.RWMutex
+	key           string
+	addr          string
+	profileFields []string
+	scriptLoaded  bool // BulkUpdateScores是否已经SCRIPT LOAD过更新脚本，见ensureScriptLoaded
 }
 
 // NewDenseLeaderboard 创建Redis密集排名排行榜
 func NewDenseLeaderboard(config Config) (*DenseRedisLeaderboard, error) {
-	client := redis.NewClient(&redis.Options{
-		Addr:     config.RedisAddr,
-		Password: config.RedisPassword,
-		DB:       config.RedisDB,
-	})
+	client, err := newUniversalClient(config)
+	if err != nil {
+		return nil, err
+	}
 
 	// 测试连接
 	ctx := context.Background()
@@ -242,36 +364,43 @@ func NewDenseLeaderboard(config Config) (*DenseRedisLeaderboard, error) {
 	}
 
 	return &DenseRedisLeaderboard{
-		redis: client,
-		key:   key,
+		redis:         client,
+		key:           key,
+		addr:          displayAddr(config),
+		profileFields: config.ProfileFields,
 	}, nil
 }
 
-// UpdateScore 更新玩家分数
-func (lb *DenseRedisLeaderboard) UpdateScore(playerID string, incrScore int64, timestamp time.Time) {
-	ctx := context.Background()
-
-	currentScore, err := lb.redis.ZScore(ctx, lb.key, playerID).Result()
-	if err != nil && err != redis.Nil {
-		fmt.Printf("Warning: failed to get current score for %s: %v\n", playerID, err)
-		currentScore = 0
+// UpdateScore 原子地更新玩家分数（通过 Lua 脚本消除 ZSCORE+ZADD 的读-改-写竞态）
+func (lb *DenseRedisLeaderboard) UpdateScore(ctx context.Context, playerID string, incrScore int64, timestamp time.Time) (*RankInfo, error) {
+	res, err := updateScoreScript.Run(ctx, lb.redis, []string{lb.key, plainKey(lb.key)}, playerID, incrScore, timestamp.UnixNano()).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to update score for %s: %v", playerID, err)
 	}
 
-	newScore := currentScore + float64(incrScore)
-	scoreWithTimestamp := -(newScore*1e9 + float64(timestamp.UnixNano())/1e9)
+	vals := res.([]interface{})
+	newScore := vals[0].(int64)
 
-	if err := lb.redis.ZAdd(ctx, lb.key, &redis.Z{
-		Score:  scoreWithTimestamp,
-		Member: playerID,
-	}).Err(); err != nil {
-		fmt.Printf("Warning: failed to update score for %s: %v\n", playerID, err)
+	// 密集排名：统计有多少玩家的打包分数不高于当前玩家（即排在其前面或就是其本人）
+	packedScore, err := lb.redis.ZScore(ctx, lb.key, playerID).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch packed score for %s: %v", playerID, err)
+	}
+	count, err := lb.redis.ZCount(ctx, lb.key, "-inf", fmt.Sprintf("%.f", packedScore)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute dense rank for %s: %v", playerID, err)
 	}
+
+	return &RankInfo{
+		PlayerID:  playerID,
+		Rank:      int(count),
+		Score:     newScore,
+		Timestamp: timestamp,
+	}, nil
 }
 
 // GetPlayerRank 获取玩家密集排名
-func (lb *DenseRedisLeaderboard) GetPlayerRank(playerID string) *RankInfo {
-	ctx := context.Background()
-
+func (lb *DenseRedisLeaderboard) GetPlayerRank(ctx context.Context, playerID string) *RankInfo {
 	score, err := lb.redis.ZScore(ctx, lb.key, playerID).Result()
 	if err != nil {
 		return nil
@@ -299,9 +428,7 @@ func (lb *DenseRedisLeaderboard) GetPlayerRank(playerID string) *RankInfo {
 }
 
 // GetTopN 获取前N名（密集排名）
-func (lb *DenseRedisLeaderboard) GetTopN(n int) []RankInfo {
-	ctx := context.Background()
-
+func (lb *DenseRedisLeaderboard) GetTopN(ctx context.Context, n int) []RankInfo {
 	members, err := lb.redis.ZRangeWithScores(ctx, lb.key, 0, int64(n-1)).Result()
 	if err != nil {
 		return nil
@@ -327,13 +454,11 @@ func (lb *DenseRedisLeaderboard) GetTopN(n int) []RankInfo {
 		})
 	}
 
-	return result
+	return attachProfiles(ctx, lb.redis, lb.key, lb.profileFields, result)
 }
 
 // GetPlayerRange 查询玩家前后N名（密集排名）
-func (lb *DenseRedisLeaderboard) GetPlayerRange(playerID string, n int) []RankInfo {
-	ctx := context.Background()
-
+func (lb *DenseRedisLeaderboard) GetPlayerRange(ctx context.Context, playerID string, n int) []RankInfo {
 	playerRank, err := lb.redis.ZRank(ctx, lb.key, playerID).Result()
 	if err != nil {
 		return nil
@@ -369,7 +494,7 @@ func (lb *DenseRedisLeaderboard) GetPlayerRange(playerID string, n int) []RankIn
 		})
 	}
 
-	return result
+	return attachProfiles(ctx, lb.redis, lb.key, lb.profileFields, result)
 }
 
 // Close 关闭Redis连接
@@ -0,0 +1,72 @@
+package leaderboard
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newTestLeaderboard 创建一个指向本地Redis的排行榜，若连接失败则跳过测试
+func newTestLeaderboard(t testing.TB, key string) *RedisLeaderboard {
+	t.Helper()
+
+	lb, err := NewLeaderboard(Config{
+		RedisAddr:      "localhost:6379",
+		LeaderboardKey: key,
+	})
+	if err != nil {
+		t.Skipf("redis not available: %v", err)
+	}
+	return lb
+}
+
+// TestUpdateScoreConcurrent 并发对同一玩家执行UpdateScore，验证原子脚本下总分正确，
+// 覆盖此前 ZSCORE+ZADD 两步操作在并发下丢失更新的问题。
+func TestUpdateScoreConcurrent(t *testing.T) {
+	lb := newTestLeaderboard(t, "test_concurrent_leaderboard")
+	defer lb.Close()
+
+	ctx := context.Background()
+	const goroutines = 50
+	const incrPerGoroutine = 10
+
+	var wg sync.WaitGroup
+	wg.Add(goroutines)
+	for i := 0; i < goroutines; i++ {
+		go func() {
+			defer wg.Done()
+			for j := 0; j < incrPerGoroutine; j++ {
+				if _, err := lb.UpdateScore(ctx, "race_player", 1, time.Now()); err != nil {
+					t.Errorf("UpdateScore failed: %v", err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	info := lb.GetPlayerRank(ctx, "race_player")
+	if info == nil {
+		t.Fatal("expected rank info for race_player, got nil")
+	}
+
+	expected := int64(goroutines * incrPerGoroutine)
+	if info.Score != expected {
+		t.Fatalf("expected score %d after concurrent updates, got %d", expected, info.Score)
+	}
+}
+
+// BenchmarkUpdateScore 衡量原子脚本路径下单次更新的开销
+func BenchmarkUpdateScore(b *testing.B) {
+	lb := newTestLeaderboard(b, "bench_leaderboard")
+	defer lb.Close()
+
+	ctx := context.Background()
+	now := time.Now()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := lb.UpdateScore(ctx, "bench_player", 1, now); err != nil {
+			b.Fatalf("UpdateScore failed: %v", err)
+		}
+	}
+}
@@ -0,0 +1,307 @@
+package leaderboard
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Period 排行榜的时间窗口类型
+type Period int
+
+const (
+	PeriodDaily Period = iota
+	PeriodWeekly
+	PeriodMonthly
+	PeriodAllTime
+)
+
+// String 返回用于拼接key的周期名
+func (p Period) String() string {
+	switch p {
+	case PeriodDaily:
+		return "daily"
+	case PeriodWeekly:
+		return "weekly"
+	case PeriodMonthly:
+		return "monthly"
+	case PeriodAllTime:
+		return "alltime"
+	default:
+		return "unknown"
+	}
+}
+
+// periodTTL 各周期桶的过期时间，到期后Redis自动清理；未出现在表中的周期（如总榜）永不过期
+var periodTTL = map[Period]time.Duration{
+	PeriodDaily:   35 * 24 * time.Hour,
+	PeriodWeekly:  10 * 7 * 24 * time.Hour,
+	PeriodMonthly: 400 * 24 * time.Hour,
+}
+
+// bucketFor 计算某个周期在给定时间点所在的日期桶
+func bucketFor(period Period, t time.Time) string {
+	switch period {
+	case PeriodDaily:
+		return t.Format("20060102")
+	case PeriodWeekly:
+		year, week := t.ISOWeek()
+		return fmt.Sprintf("%04dW%02d", year, week)
+	case PeriodMonthly:
+		return t.Format("200601")
+	default: // PeriodAllTime
+		return "all"
+	}
+}
+
+// leaderboardKey 按 lb:{game}:period:bucket 的形式拼接key。
+// gameID 用花括号包裹作为哈希标签，使同一游戏下所有周期桶落在同一哈希槽，
+// 便于Cluster模式下的MULTI/Pipeline和跨周期的组合排行榜聚合。
+func leaderboardKey(namespace, gameID string, period Period, t time.Time) string {
+	return fmt.Sprintf("%s:{%s}:%s:%s", namespace, gameID, period, bucketFor(period, t))
+}
+
+// ManagerConfig LeaderboardManager 配置
+type ManagerConfig struct {
+	Config // 复用底层Redis连接配置（RedisAddr/Mode/Addrs/PoolSize等）
+
+	Namespace     string   // key前缀，默认 "lb"
+	ActivePeriods []Period // UpdateScore需要同时写入的周期桶，默认 daily/weekly/monthly/alltime
+	RolloverHour  int      // 预热下一天桶的小时（0-23，本地时区），默认0点
+}
+
+// LeaderboardManager 多租户排行榜管理器：按 (namespace, gameID, period) 生成独立的
+// 排行榜句柄，并支持一次分数提交原子地写入该游戏下所有活跃的周期桶。
+type LeaderboardManager struct {
+	redis         redis.UniversalClient
+	namespace     string
+	activePeriods []Period
+	rolloverHour  int
+	profileFields []string // 透传给每个句柄的Config.ProfileFields，限制GetTopN/GetPlayerRange拉取的资料字段
+
+	mutex        sync.RWMutex
+	handles      map[string]LeaderboardService
+	seenGames    map[string]struct{}
+	scriptLoaded bool
+	stopCh       chan struct{}
+}
+
+// NewLeaderboardManager 创建多租户排行榜管理器，并启动后台的次日桶预热协程
+func NewLeaderboardManager(config ManagerConfig) (*LeaderboardManager, error) {
+	client, err := newUniversalClient(config.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	if err := client.Ping(ctx).Err(); err != nil {
+		return nil, fmt.Errorf("failed to connect to Redis: %v", err)
+	}
+	if err := updateScoreScript.Load(ctx, client).Err(); err != nil {
+		return nil, fmt.Errorf("failed to load update script: %v", err)
+	}
+
+	namespace := config.Namespace
+	if namespace == "" {
+		namespace = "lb"
+	}
+
+	periods := config.ActivePeriods
+	if len(periods) == 0 {
+		periods = []Period{PeriodDaily, PeriodWeekly, PeriodMonthly, PeriodAllTime}
+	}
+
+	m := &LeaderboardManager{
+		redis:         client,
+		namespace:     namespace,
+		activePeriods: periods,
+		rolloverHour:  config.RolloverHour,
+		profileFields: config.ProfileFields,
+		handles:       make(map[string]LeaderboardService),
+		seenGames:     make(map[string]struct{}),
+		scriptLoaded:  true,
+		stopCh:        make(chan struct{}),
+	}
+
+	go m.rolloverLoop()
+
+	return m, nil
+}
+
+// Get 返回 gameID 在 period 当前桶上的排行榜句柄
+func (m *LeaderboardManager) Get(gameID string, period Period) LeaderboardService {
+	return m.handleFor(gameID, period, time.Now())
+}
+
+// handleFor 返回（并按需创建/缓存）gameID 在 period、时间点 t 所在桶上的排行榜句柄
+func (m *LeaderboardManager) handleFor(gameID string, period Period, t time.Time) LeaderboardService {
+	key := leaderboardKey(m.namespace, gameID, period, t)
+
+	m.mutex.RLock()
+	lb, ok := m.handles[key]
+	m.mutex.RUnlock()
+	if ok {
+		return lb
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if lb, ok := m.handles[key]; ok {
+		return lb
+	}
+
+	lb = &RedisLeaderboard{redis: m.redis, key: key, profileFields: m.profileFields}
+	m.handles[key] = lb
+	m.seenGames[gameID] = struct{}{}
+	return lb
+}
+
+// UpdateScore 把一次分数提交原子地写入该游戏下所有活跃周期桶（日/周/月/总榜等），
+// 通过一次 TxPipelined（MULTI/EXEC）对每个桶执行原子更新脚本，保证同一次提交在各
+// 周期维度上要么全部生效、要么全部不生效。这依赖于所有桶key共用同一个 {gameID}
+// 哈希标签、落在同一个槽位/节点上，否则Cluster模式下无法纳入同一个事务。
+func (m *LeaderboardManager) UpdateScore(ctx context.Context, gameID, playerID string, incrScore int64, timestamp time.Time) (map[Period]*RankInfo, error) {
+	results, err := m.execUpdateScore(ctx, gameID, playerID, incrScore, timestamp)
+	if err != nil && isNoScriptErr(err) {
+		m.mutex.Lock()
+		m.scriptLoaded = false
+		m.mutex.Unlock()
+		results, err = m.execUpdateScore(ctx, gameID, playerID, incrScore, timestamp)
+	}
+	return results, err
+}
+
+// execUpdateScore 实际执行一次TxPipelined更新；脚本未加载时先按需加载（仅加载一次，
+// 而不是每次调用都往返一次 SCRIPT LOAD）。
+func (m *LeaderboardManager) execUpdateScore(ctx context.Context, gameID, playerID string, incrScore int64, timestamp time.Time) (map[Period]*RankInfo, error) {
+	if err := m.ensureScriptLoaded(ctx); err != nil {
+		return nil, fmt.Errorf("failed to load update script: %v", err)
+	}
+
+	type pendingCmd struct {
+		period Period
+		cmd    *redis.Cmd
+	}
+	pending := make([]pendingCmd, 0, len(m.activePeriods))
+
+	_, err := m.redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, period := range m.activePeriods {
+			key := leaderboardKey(m.namespace, gameID, period, timestamp)
+			cmd := pipe.EvalSha(ctx, updateScoreScript.Hash(), []string{key, plainKey(key)}, playerID, incrScore, timestamp.UnixNano())
+			pending = append(pending, pendingCmd{period: period, cmd: cmd})
+
+			if ttl, ok := periodTTL[period]; ok {
+				pipe.Expire(ctx, key, ttl)
+				pipe.Expire(ctx, plainKey(key), ttl)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to update score for game=%s player=%s: %v", gameID, playerID, err)
+	}
+
+	m.mutex.Lock()
+	m.seenGames[gameID] = struct{}{}
+	m.mutex.Unlock()
+
+	results := make(map[Period]*RankInfo, len(pending))
+	for _, p := range pending {
+		res, err := p.cmd.Result()
+		if err != nil {
+			return nil, fmt.Errorf("failed to update %s bucket for %s: %v", p.period, playerID, err)
+		}
+
+		vals := res.([]interface{})
+		newScore := vals[0].(int64)
+		rank := int64(0)
+		if vals[1] != nil {
+			rank = vals[1].(int64) + 1
+		}
+
+		results[p.period] = &RankInfo{
+			PlayerID:  playerID,
+			Rank:      int(rank),
+			Score:     newScore,
+			Timestamp: timestamp,
+		}
+	}
+
+	return results, nil
+}
+
+// ensureScriptLoaded 确保更新脚本已经 SCRIPT LOAD 过，成功后只加载一次；
+// 仅在探测到 NOSCRIPT（例如Redis重启清空了脚本缓存）时才会被要求重新加载。
+func (m *LeaderboardManager) ensureScriptLoaded(ctx context.Context) error {
+	m.mutex.RLock()
+	loaded := m.scriptLoaded
+	m.mutex.RUnlock()
+	if loaded {
+		return nil
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if m.scriptLoaded {
+		return nil
+	}
+	if err := updateScoreScript.Load(ctx, m.redis).Err(); err != nil {
+		return err
+	}
+	m.scriptLoaded = true
+	return nil
+}
+
+// isNoScriptErr 判断错误是否为 Redis 的 NOSCRIPT（脚本缓存被清空，需要重新LOAD）
+func isNoScriptErr(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "NOSCRIPT")
+}
+
+// rolloverLoop 每天到达 rolloverHour 时，为所有已出现过的游戏预热次日的排行榜句柄，
+// 避免次日第一次请求时才去创建句柄。
+func (m *LeaderboardManager) rolloverLoop() {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	lastRolloverDate := ""
+	for {
+		select {
+		case <-m.stopCh:
+			return
+		case now := <-ticker.C:
+			if now.Hour() != m.rolloverHour {
+				continue
+			}
+
+			today := now.Format("20060102")
+			if today == lastRolloverDate {
+				continue
+			}
+			lastRolloverDate = today
+
+			tomorrow := now.AddDate(0, 0, 1)
+			m.mutex.RLock()
+			games := make([]string, 0, len(m.seenGames))
+			for game := range m.seenGames {
+				games = append(games, game)
+			}
+			m.mutex.RUnlock()
+
+			for _, game := range games {
+				for _, period := range m.activePeriods {
+					m.handleFor(game, period, tomorrow)
+				}
+			}
+		}
+	}
+}
+
+// Close 停止后台预热协程并关闭底层Redis连接
+func (m *LeaderboardManager) Close() error {
+	close(m.stopCh)
+	return m.redis.Close()
+}
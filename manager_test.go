@@ -0,0 +1,40 @@
+package leaderboard
+
+import (
+	"testing"
+	"time"
+)
+
+// TestBucketFor 验证各周期的日期桶格式，尤其是跨年ISO周的边界情况
+func TestBucketFor(t *testing.T) {
+	cases := []struct {
+		period Period
+		t      time.Time
+		want   string
+	}{
+		{PeriodDaily, time.Date(2026, 3, 5, 0, 0, 0, 0, time.UTC), "20260305"},
+		{PeriodWeekly, time.Date(2026, 3, 5, 0, 0, 0, 0, time.UTC), "2026W10"},
+		{PeriodMonthly, time.Date(2026, 3, 5, 0, 0, 0, 0, time.UTC), "202603"},
+		{PeriodAllTime, time.Date(2026, 3, 5, 0, 0, 0, 0, time.UTC), "all"},
+		// 2026-01-01是周四，ISO周归属到2026年第1周
+		{PeriodWeekly, time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), "2026W01"},
+		// 2025-12-31是周三，ISO周归属到2026年第1周（跨年）
+		{PeriodWeekly, time.Date(2025, 12, 31, 0, 0, 0, 0, time.UTC), "2026W01"},
+	}
+
+	for _, c := range cases {
+		if got := bucketFor(c.period, c.t); got != c.want {
+			t.Errorf("bucketFor(%v, %v) = %q, want %q", c.period, c.t, got, c.want)
+		}
+	}
+}
+
+// TestLeaderboardKey 验证key拼接格式，尤其是gameID必须被花括号包裹作为哈希标签
+func TestLeaderboardKey(t *testing.T) {
+	ts := time.Date(2026, 3, 5, 0, 0, 0, 0, time.UTC)
+	got := leaderboardKey("lb", "game1", PeriodDaily, ts)
+	want := "lb:{game1}:daily:20260305"
+	if got != want {
+		t.Fatalf("leaderboardKey() = %q, want %q", got, want)
+	}
+}
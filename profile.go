@@ -0,0 +1,106 @@
+package leaderboard
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// profileKey 返回某个玩家资料HASH的key
+func profileKey(leaderboardKey, playerID string) string {
+	return leaderboardKey + ":profile:" + playerID
+}
+
+// SetPlayerProfile 写入玩家资料（昵称、头像URL、国家等），存储在一个独立的HASH中
+func (lb *RedisLeaderboard) SetPlayerProfile(ctx context.Context, playerID string, fields map[string]string) error {
+	if err := setPlayerProfile(ctx, lb.redis, lb.key, playerID, fields); err != nil {
+		return err
+	}
+	return nil
+}
+
+// SetPlayerProfile 写入玩家资料（昵称、头像URL、国家等），存储在一个独立的HASH中
+func (lb *DenseRedisLeaderboard) SetPlayerProfile(ctx context.Context, playerID string, fields map[string]string) error {
+	if err := setPlayerProfile(ctx, lb.redis, lb.key, playerID, fields); err != nil {
+		return err
+	}
+	return nil
+}
+
+func setPlayerProfile(ctx context.Context, client redis.UniversalClient, leaderboardKey, playerID string, fields map[string]string) error {
+	if len(fields) == 0 {
+		return nil
+	}
+
+	args := make([]interface{}, 0, len(fields)*2)
+	for field, value := range fields {
+		args = append(args, field, value)
+	}
+
+	if err := client.HSet(ctx, profileKey(leaderboardKey, playerID), args...).Err(); err != nil {
+		return fmt.Errorf("failed to set profile for %s: %v", playerID, err)
+	}
+	return nil
+}
+
+// buildProfileFromHMGet 把一次HMGET的结果（vals与fields按下标一一对应）组装成资料
+// map，跳过HASH中不存在的字段（HMGET对应位置为nil）。
+func buildProfileFromHMGet(fields []string, vals []interface{}) map[string]string {
+	profile := make(map[string]string, len(fields))
+	for j, field := range fields {
+		if v, ok := vals[j].(string); ok {
+			profile[field] = v
+		}
+	}
+	return profile
+}
+
+// attachProfiles 在一次Pipeline中为results里的每个玩家拉取资料HASH，填充RankInfo.Profile，
+// 避免对每个玩家单独发起一次HGETALL/HMGET往返。fields留空则拉取资料HASH的全部字段。
+func attachProfiles(ctx context.Context, client redis.UniversalClient, leaderboardKey string, fields []string, results []RankInfo) []RankInfo {
+	if len(results) == 0 {
+		return results
+	}
+
+	pipe := client.Pipeline()
+
+	if len(fields) > 0 {
+		cmds := make([]*redis.SliceCmd, len(results))
+		for i, r := range results {
+			cmds[i] = pipe.HMGet(ctx, profileKey(leaderboardKey, r.PlayerID), fields...)
+		}
+		if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+			return results
+		}
+
+		for i := range results {
+			vals, err := cmds[i].Result()
+			if err != nil {
+				continue
+			}
+			if profile := buildProfileFromHMGet(fields, vals); len(profile) > 0 {
+				results[i].Profile = profile
+			}
+		}
+		return results
+	}
+
+	cmds := make([]*redis.StringStringMapCmd, len(results))
+	for i, r := range results {
+		cmds[i] = pipe.HGetAll(ctx, profileKey(leaderboardKey, r.PlayerID))
+	}
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return results
+	}
+
+	for i := range results {
+		profile, err := cmds[i].Result()
+		if err != nil || len(profile) == 0 {
+			continue
+		}
+		results[i].Profile = profile
+	}
+
+	return results
+}
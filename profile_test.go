@@ -0,0 +1,35 @@
+package leaderboard
+
+import "testing"
+
+// TestBuildProfileFromHMGet 验证HMGET结果按字段下标组装，且跳过HASH中缺失的字段（nil）
+func TestBuildProfileFromHMGet(t *testing.T) {
+	fields := []string{"nickname", "avatar", "country"}
+	vals := []interface{}{"Alice", nil, "US"}
+
+	got := buildProfileFromHMGet(fields, vals)
+
+	want := map[string]string{"nickname": "Alice", "country": "US"}
+	if len(got) != len(want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Fatalf("expected %s=%q, got %q", k, v, got[k])
+		}
+	}
+	if _, ok := got["avatar"]; ok {
+		t.Fatalf("expected missing field avatar to be absent, got %v", got["avatar"])
+	}
+}
+
+// TestBuildProfileFromHMGetAllMissing 所有字段都缺失时应返回空map，而不是nil导致的panic
+func TestBuildProfileFromHMGetAllMissing(t *testing.T) {
+	fields := []string{"nickname"}
+	vals := []interface{}{nil}
+
+	got := buildProfileFromHMGet(fields, vals)
+	if len(got) != 0 {
+		t.Fatalf("expected empty profile, got %v", got)
+	}
+}